@@ -0,0 +1,126 @@
+package buffstream
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrAsyncWriterClosed is returned by AsyncWriter.Write once the AsyncWriter has been closed
+var ErrAsyncWriterClosed = errors.New("AsyncWriter is closed")
+
+type asyncMsg struct {
+	msgType int
+	data    []byte
+}
+
+// AsyncWriter wraps a Writer with a bounded queue serviced by a single dedicated goroutine, so callers can enqueue
+// messages without blocking on the underlying connection's I/O. Once the underlying Writer returns an error, the
+// background goroutine stops and every subsequent Write returns that error. AsyncWriter is best-effort: a Write
+// that races exactly with the background goroutine failing or with Close may return nil for a message that is then
+// never delivered, since acknowledging enqueue and acknowledging delivery happen at different times.
+type AsyncWriter struct {
+	queue chan asyncMsg
+	stop  chan struct{}
+	done  chan struct{}
+
+	closeOnce sync.Once
+	mu        sync.Mutex
+	err       error
+}
+
+// NewAsyncWriter creates an AsyncWriter that writes to w from a dedicated goroutine, buffering up to queueSize
+// pending messages before Write blocks. Writer options apply to the underlying Writer.
+func NewAsyncWriter(w io.Writer, queueSize int, opts ...WriterOption) *AsyncWriter {
+	aw := &AsyncWriter{
+		queue: make(chan asyncMsg, queueSize),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	go aw.run(NewWriter(w, opts...))
+
+	return aw
+}
+
+// run drains the queue until either a write fails or stop is closed, at which point it drains whatever is left in
+// the queue without blocking before exiting. The queue itself is never closed, so Write can never panic by sending
+// on a closed channel.
+func (aw *AsyncWriter) run(writer *Writer) {
+	defer close(aw.done)
+
+	for {
+		select {
+		case m := <-aw.queue:
+			if _, err := writer.Write(m.msgType, m.data); err != nil {
+				aw.mu.Lock()
+				aw.err = err
+				aw.mu.Unlock()
+				return
+			}
+		case <-aw.stop:
+			for {
+				select {
+				case m := <-aw.queue:
+					if _, err := writer.Write(m.msgType, m.data); err != nil {
+						aw.mu.Lock()
+						aw.err = err
+						aw.mu.Unlock()
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// Write enqueues data to be written as a message of the given type and returns immediately. It blocks only if the
+// queue is full, and returns an error without enqueuing once a previous write has failed or Close has been called.
+func (aw *AsyncWriter) Write(msgType int, data []byte) error {
+	// Checked on its own first so that a failed writer is always reported, even while the queue still has room.
+	select {
+	case <-aw.done:
+		aw.mu.Lock()
+		defer aw.mu.Unlock()
+		if aw.err != nil {
+			return aw.err
+		}
+		return ErrAsyncWriterClosed
+	default:
+	}
+
+	select {
+	case <-aw.stop:
+		return ErrAsyncWriterClosed
+	default:
+	}
+
+	select {
+	case aw.queue <- asyncMsg{msgType: msgType, data: data}:
+		return nil
+	case <-aw.stop:
+		return ErrAsyncWriterClosed
+	case <-aw.done:
+		aw.mu.Lock()
+		defer aw.mu.Unlock()
+		if aw.err != nil {
+			return aw.err
+		}
+		return ErrAsyncWriterClosed
+	}
+}
+
+// Close stops accepting new messages, waits for the queue to drain (or for the writer goroutine to fail), and
+// returns the first error the writer goroutine encountered, if any.
+func (aw *AsyncWriter) Close() error {
+	aw.closeOnce.Do(func() {
+		close(aw.stop)
+	})
+	<-aw.done
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	return aw.err
+}
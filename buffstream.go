@@ -4,14 +4,25 @@ package buffstream
 
 import (
 	"bufio"
-	"encoding/binary"
 	"errors"
 	"io"
+	"sync"
 )
 
 var (
 	// ErrBufferTooSmall is returned when the buffer passed in for reading is too small to contain the whole message
 	ErrBufferTooSmall = errors.New("Read buffer too small, please try again with a bigger buffer")
+
+	// ErrMessageTooLarge is returned when the incoming message's declared length exceeds the Reader's MaxMessageSize
+	ErrMessageTooLarge = errors.New("message size exceeds the Reader's configured maximum")
+
+	// ErrReadRequiresReadMsg is returned by Read when the Reader is configured with WithDecompression, since a
+	// decompressed message's size can't be predicted by the caller ahead of time
+	ErrReadRequiresReadMsg = errors.New("Reader is configured for decompression, use ReadMsg instead of Read")
+
+	// ErrInvalidMessageLength is returned when the decoded header declares a negative message length, which can
+	// only come from a malformed or malicious peer
+	ErrInvalidMessageLength = errors.New("message declared a negative length")
 )
 
 type byteReader interface {
@@ -20,23 +31,43 @@ type byteReader interface {
 }
 
 // Reader wraps an io.Reader, each call to Read will return exactly one message, the length of which will exactly match the length of the buffer written on the other end
+//
+// Reader is not safe for concurrent use: Read and ReadMsg mutate internal state (the saved header left behind by
+// ErrBufferTooSmall) that must be read back by the next call on the same goroutine. Serialize access to a Reader
+// yourself if multiple goroutines need to consume it.
 type Reader struct {
 	reader byteReader
+	pool   Pool
+	codec  FrameCodec
 
 	isSaved      bool
 	savedMsgLen  int64
 	savedMsgType int64
+
+	maxMessageSize int64
+	checksum       bool
+	decompress     bool
 }
 
 // Writer wraps an io.Writer, each call to Write will write exactly one message, and the Read on the other hand will return the exact same message with the same length
+//
+// Writer is safe for concurrent use: Write takes an internal lock so that the header and body of concurrent calls
+// are never interleaved on the wire. Concurrent calls are still serialized onto the underlying io.Writer one at a
+// time, so a slow write still blocks other goroutines; use NewAsyncWriter if callers shouldn't block on I/O.
 type Writer struct {
-	writer  io.Writer
-	msgLen  [10]byte
-	msgType [10]byte
+	writer io.Writer
+	pool   Pool
+	codec  FrameCodec
+
+	checksum          bool
+	compressThreshold int
+
+	mu sync.Mutex
 }
 
-// NewReader creates a new reader
-func NewReader(r io.Reader) *Reader {
+// NewReader creates a new reader, applying the given options. By default headers are decoded with VarintCodec and
+// there is no message size limit.
+func NewReader(r io.Reader, opts ...ReaderOption) *Reader {
 	var br byteReader
 	if rr, ok := r.(byteReader); ok {
 		br = rr
@@ -44,17 +75,50 @@ func NewReader(r io.Reader) *Reader {
 		br = bufio.NewReader(r)
 	}
 
-	return &Reader{
+	reader := &Reader{
 		reader:      br,
+		pool:        NewPool(),
+		codec:       VarintCodec{},
 		savedMsgLen: -1,
 	}
+
+	for _, opt := range opts {
+		opt(reader)
+	}
+
+	return reader
+}
+
+// NewReaderPool creates a new reader that draws its message buffers for ReadMsg from pool instead of the default Pool.
+func NewReaderPool(r io.Reader, pool Pool) *Reader {
+	return NewReader(r, WithReaderPool(pool))
+}
+
+// NewReaderSize creates a new reader whose Read will return ErrMessageTooLarge instead of reading any message
+// whose declared length exceeds maxMessageSize. The body of an oversized message is discarded from the underlying
+// stream so the Reader remains usable for subsequent calls. A maxMessageSize of 0 disables the check.
+func NewReaderSize(r io.Reader, maxMessageSize int64) *Reader {
+	return NewReader(r, WithMaxMessageSize(maxMessageSize))
 }
 
-// NewWriter creates a new writer
-func NewWriter(w io.Writer) *Writer {
-	return &Writer{
+// NewWriter creates a new writer, applying the given options. By default headers are encoded with VarintCodec.
+func NewWriter(w io.Writer, opts ...WriterOption) *Writer {
+	writer := &Writer{
 		writer: w,
+		pool:   NewPool(),
+		codec:  VarintCodec{},
+	}
+
+	for _, opt := range opts {
+		opt(writer)
 	}
+
+	return writer
+}
+
+// NewWriterPool creates a new writer that draws its scratch buffers from pool instead of the default Pool.
+func NewWriterPool(w io.Writer, pool Pool) *Writer {
+	return NewWriter(w, WithWriterPool(pool))
 }
 
 // Write allows you to send a stream of bytes as messages. Each slice of bytes
@@ -62,41 +126,123 @@ func NewWriter(w io.Writer) *Writer {
 // trying until the full message is delivered, or the connection is broken.
 // By default Write is unbuffered, if buffered IO is desirable, the passed in reader can be wrapped with a bufio.Writer
 func (w *Writer) Write(msgType int, data []byte) (int, error) {
-	msgLenBytes := binary.PutVarint(w.msgLen[:], int64(len(data)))
-	msgTypeBytes := binary.PutVarint(w.msgType[:], int64(msgType))
+	w.mu.Lock()
+	defer w.mu.Unlock()
 
-	_, err := w.writer.Write(w.msgLen[:msgLenBytes])
-	if err != nil {
+	body := data
+	if w.compressThreshold > 0 {
+		payload := data
+		flag := notCompressed
+
+		if len(data) >= w.compressThreshold {
+			compressed, err := gzipCompress(w.pool, data)
+			if err != nil {
+				return 0, err
+			}
+			defer w.pool.Put(compressed)
+			payload = compressed
+			flag = isCompressed
+		}
+
+		// Prepend a flag byte so the Reader knows whether this particular message was compressed without having
+		// to guess from the body's contents, which could collide with a legitimate uncompressed payload.
+		flagged := w.pool.Get(len(payload) + 1)
+		defer w.pool.Put(flagged)
+		flagged[0] = flag
+		copy(flagged[1:], payload)
+		body = flagged
+	}
+
+	if w.checksum {
+		withChecksum := appendChecksum(w.pool, body)
+		defer w.pool.Put(withChecksum)
+		body = withChecksum
+	}
+
+	if err := w.codec.EncodeHeader(w.writer, msgType, len(body)); err != nil {
 		return 0, err
 	}
 
-	_, err = w.writer.Write(w.msgType[:msgTypeBytes])
-	if err != nil {
+	if _, err := w.writer.Write(body); err != nil {
 		return 0, err
 	}
 
-	return w.writer.Write(data)
+	return len(data), nil
 }
 
-// Read will read the next pending message and return its type and length, or an error if any occured
-func (r *Reader) Read(data []byte) (int, int, error) {
-	var msgLen int64
-	var msgType int64
+// readHeader returns the msgLen and msgType of the next pending message, consuming a saved header left over from a
+// previous ErrBufferTooSmall if there is one. It enforces maxMessageSize, discarding the body of an oversized
+// message so the underlying stream stays usable.
+func (r *Reader) readHeader() (int64, int64, error) {
 	if r.isSaved {
-		msgLen = r.savedMsgLen
-		msgType = r.savedMsgType
+		msgLen, msgType := r.savedMsgLen, r.savedMsgType
 		r.isSaved = false
-	} else {
-		var err error
-		msgLen, err = binary.ReadVarint(r.reader)
-		if err != nil {
+		return msgLen, msgType, nil
+	}
+
+	msgType, msgLen, err := r.codec.DecodeHeader(r.reader)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if msgLen < 0 {
+		return 0, 0, ErrInvalidMessageLength
+	}
+
+	if r.maxMessageSize > 0 && int64(msgLen) > r.maxMessageSize {
+		if _, err := io.CopyN(io.Discard, r.reader, int64(msgLen)); err != nil {
+			return 0, 0, err
+		}
+		return 0, 0, ErrMessageTooLarge
+	}
+
+	return int64(msgLen), int64(msgType), nil
+}
+
+// Read will read the next pending message and return its type and length, or an error if any occured
+func (r *Reader) Read(data []byte) (int, int, error) {
+	if r.decompress {
+		return 0, 0, ErrReadRequiresReadMsg
+	}
+
+	msgLen, msgType, err := r.readHeader()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if r.checksum {
+		// The message's on-wire length includes the checksum trailer, but the caller's buffer only needs to fit
+		// the payload, so size the buffer-too-small check against the payload length rather than the wire length.
+		payloadLen := int(msgLen) - checksumSize
+		if payloadLen < 0 {
+			if _, err := io.CopyN(io.Discard, r.reader, msgLen); err != nil {
+				return 0, 0, err
+			}
+			return 0, 0, ErrChecksumMismatch
+		}
+
+		if payloadLen > len(data) {
+			r.isSaved = true
+			r.savedMsgLen = msgLen
+			r.savedMsgType = msgType
+			return 0, 0, ErrBufferTooSmall
+		}
+
+		buf := r.pool.Get(int(msgLen))
+		defer r.pool.Put(buf)
+
+		if _, err := io.ReadFull(r.reader, buf); err != nil {
 			return 0, 0, err
 		}
 
-		msgType, err = binary.ReadVarint(r.reader)
+		n, err := stripChecksum(buf)
 		if err != nil {
 			return 0, 0, err
 		}
+
+		copy(data, buf[:n])
+
+		return int(msgType), n, nil
 	}
 
 	if msgLen > int64(len(data)) {
@@ -108,5 +254,65 @@ func (r *Reader) Read(data []byte) (int, int, error) {
 
 	// Using the header, read the remaining body
 	readLen, err := io.ReadFull(r.reader, data[:msgLen])
-	return int(msgType), readLen, err
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(msgType), readLen, nil
+}
+
+// ReadMsg reads the next pending message, allocating a buffer sized to exactly fit it from the Reader's Pool instead
+// of requiring the caller to guess a buffer size up front. The caller must invoke the returned release func once it
+// is done with data, returning the buffer to the Pool.
+func (r *Reader) ReadMsg() (msgType int, data []byte, release func(), err error) {
+	msgLen, msgTyp, err := r.readHeader()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	buf := r.pool.Get(int(msgLen))
+	if _, err := io.ReadFull(r.reader, buf); err != nil {
+		r.pool.Put(buf)
+		return 0, nil, nil, err
+	}
+	release = func() {
+		r.pool.Put(buf)
+	}
+
+	body := buf
+	if r.checksum {
+		n, err := stripChecksum(body)
+		if err != nil {
+			release()
+			return 0, nil, nil, err
+		}
+		body = body[:n]
+	}
+
+	if r.decompress {
+		if len(body) < 1 {
+			release()
+			return 0, nil, nil, io.ErrUnexpectedEOF
+		}
+
+		flag := body[0]
+		body = body[1:]
+
+		if flag == isCompressed {
+			decoded, err := gunzip(r.pool, body, r.maxMessageSize)
+			if err != nil {
+				release()
+				return 0, nil, nil, err
+			}
+
+			prevRelease := release
+			release = func() {
+				r.pool.Put(decoded)
+				prevRelease()
+			}
+			body = decoded
+		}
+	}
+
+	return int(msgTyp), body, release, nil
 }
@@ -0,0 +1,48 @@
+package buffstream
+
+import (
+	"bytes"
+	"testing"
+)
+
+func benchWriteMsg(b *testing.B, size int) []byte {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+	data := make([]byte, size)
+	for i := 0; i < b.N; i++ {
+		if _, err := writer.Write(0, data); err != nil {
+			b.Fatalf("Error while writing: %v", err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+func BenchmarkRead(b *testing.B) {
+	const size = 1024
+	wire := benchWriteMsg(b, size)
+	reader := NewReader(bytes.NewReader(wire))
+	buf := make([]byte, size)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := reader.Read(buf); err != nil {
+			b.Fatalf("Error while reading: %v", err)
+		}
+	}
+}
+
+func BenchmarkReadMsg(b *testing.B) {
+	const size = 1024
+	wire := benchWriteMsg(b, size)
+	reader := NewReader(bytes.NewReader(wire))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, release, err := reader.ReadMsg()
+		if err != nil {
+			b.Fatalf("Error while reading: %v", err)
+		}
+		release()
+	}
+}
@@ -99,6 +99,61 @@ func TestTCPConn(t *testing.T) {
 	testReaderWriter(t, connDial, connListen)
 }
 
+func TestMessageTooLarge(t *testing.T) {
+	r, w := io.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	msgType := rand.Intn(50)
+	oversizedData := make([]byte, 50)
+	if _, err := rand.Read(oversizedData); err != nil {
+		t.Fatalf("Error while reading random bytes: %v", err)
+	}
+
+	smallData := make([]byte, 5)
+	if _, err := rand.Read(smallData); err != nil {
+		t.Fatalf("Error while reading random bytes: %v", err)
+	}
+
+	go func() {
+		defer wg.Done()
+		buffWriter := NewWriter(w)
+		if _, err := buffWriter.Write(msgType, oversizedData); err != nil {
+			t.Fatalf("Error while writing: %v", err)
+		}
+
+		if _, err := buffWriter.Write(msgType, smallData); err != nil {
+			t.Fatalf("Error while writing: %v", err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		buffReader := NewReaderSize(r, 10)
+		buf := make([]byte, 50)
+
+		if _, _, err := buffReader.Read(buf); err != ErrMessageTooLarge {
+			t.Fatalf("Expected ErrMessageTooLarge, got %v", err)
+		}
+
+		// The Reader must still be usable for the next message
+		gotMsgType, gotMsgLen, err := buffReader.Read(buf)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if gotMsgType != msgType {
+			t.Fatalf("Expected type %v, got %v", msgType, gotMsgType)
+		}
+
+		if len(smallData) != gotMsgLen || !bytes.Equal(buf[:gotMsgLen], smallData) {
+			t.Fatalf("message sent and recieved not identical")
+		}
+	}()
+
+	wg.Wait()
+}
+
 func TestRereadTooShort(t *testing.T) {
 	r, w := io.Pipe()
 	var wg sync.WaitGroup
@@ -144,3 +199,51 @@ func TestRereadTooShort(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestReadMsg(t *testing.T) {
+	r, w := io.Pipe()
+	msgs := make([]msg, 10)
+	for i := 0; i < len(msgs); i++ {
+		msgs[i].msgType = rand.Intn(50)
+		msgs[i].data = make([]byte, rand.Intn(50))
+		if _, err := rand.Read(msgs[i].data); err != nil {
+			t.Fatalf("Error while reading random bytes: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		buffWriter := NewWriter(w)
+		for i, m := range msgs {
+			if _, err := buffWriter.Write(m.msgType, m.data); err != nil {
+				t.Fatalf("Error while writing message %v: %v", i, err)
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		buffReader := NewReader(r)
+		for i, m := range msgs {
+			gotMsgType, data, release, err := buffReader.ReadMsg()
+			if err != nil {
+				t.Fatalf("Error while reading message %v: %v", i, err)
+			}
+
+			if gotMsgType != m.msgType {
+				t.Fatalf("Error on message %v, expected type %v, got %v", i, m.msgType, gotMsgType)
+			}
+
+			if !bytes.Equal(data, m.data) {
+				t.Fatalf("Error on message %v, message sent and recieved not identical", i)
+			}
+
+			release()
+		}
+	}()
+
+	wg.Wait()
+}
@@ -0,0 +1,38 @@
+package buffstream
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+)
+
+// ErrChecksumMismatch is returned when a message body's trailing CRC32 checksum does not match its contents,
+// indicating the message was corrupted in transit
+var ErrChecksumMismatch = errors.New("message body failed its CRC32 checksum")
+
+const checksumSize = 4
+
+// appendChecksum copies body into a buffer drawn from pool with a trailing big-endian CRC32 checksum appended,
+// leaving body itself untouched.
+func appendChecksum(pool Pool, body []byte) []byte {
+	buf := pool.Get(len(body) + checksumSize)
+	copy(buf, body)
+	binary.BigEndian.PutUint32(buf[len(body):], crc32.ChecksumIEEE(body))
+	return buf
+}
+
+// stripChecksum verifies body's trailing CRC32 checksum and returns the length of body with the trailer removed.
+func stripChecksum(body []byte) (int, error) {
+	if len(body) < checksumSize {
+		return 0, ErrChecksumMismatch
+	}
+
+	n := len(body) - checksumSize
+	want := binary.BigEndian.Uint32(body[n:])
+	got := crc32.ChecksumIEEE(body[:n])
+	if want != got {
+		return 0, ErrChecksumMismatch
+	}
+
+	return n, nil
+}
@@ -0,0 +1,198 @@
+package buffstream
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestFixedCodec(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, WithWriterCodec(FixedCodec{}))
+	reader := NewReader(&buf, WithReaderCodec(FixedCodec{}))
+
+	msgType := rand.Intn(50)
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("Error while reading random bytes: %v", err)
+	}
+
+	if _, err := writer.Write(msgType, data); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	readBuf := make([]byte, 32)
+	gotMsgType, gotMsgLen, err := reader.Read(readBuf)
+	if err != nil {
+		t.Fatalf("Error while reading: %v", err)
+	}
+
+	if gotMsgType != msgType {
+		t.Fatalf("Expected type %v, got %v", msgType, gotMsgType)
+	}
+
+	if !bytes.Equal(readBuf[:gotMsgLen], data) {
+		t.Fatalf("message sent and recieved not identical")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, WithChecksumWriter())
+	reader := NewReader(&buf, WithChecksumReader())
+
+	data := []byte("hello world")
+	if _, err := writer.Write(1, data); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	// The buffer only needs to fit the payload; Read accounts for the checksum trailer internally.
+	readBuf := make([]byte, len(data))
+	_, gotMsgLen, err := reader.Read(readBuf)
+	if err != nil {
+		t.Fatalf("Error while reading: %v", err)
+	}
+
+	if !bytes.Equal(readBuf[:gotMsgLen], data) {
+		t.Fatalf("message sent and recieved not identical")
+	}
+}
+
+func TestChecksumBufferTooSmallThenRetry(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, WithChecksumWriter())
+	reader := NewReader(&buf, WithChecksumReader())
+
+	data := []byte("hello world")
+	if _, err := writer.Write(1, data); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	if _, _, err := reader.Read(make([]byte, 4)); err != ErrBufferTooSmall {
+		t.Fatalf("Expected ErrBufferTooSmall, got %v", err)
+	}
+
+	readBuf := make([]byte, len(data))
+	_, gotMsgLen, err := reader.Read(readBuf)
+	if err != nil {
+		t.Fatalf("Error while reading after retry: %v", err)
+	}
+
+	if !bytes.Equal(readBuf[:gotMsgLen], data) {
+		t.Fatalf("message sent and recieved not identical")
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, WithChecksumWriter())
+	reader := NewReader(&buf, WithChecksumReader())
+
+	if _, err := writer.Write(1, []byte("hello world")); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	// Corrupt a byte in the middle of the buffered wire data
+	corrupted := buf.Bytes()
+	corrupted[len(corrupted)/2] ^= 0xff
+
+	readBuf := make([]byte, 32)
+	if _, _, err := reader.Read(readBuf); err != ErrChecksumMismatch {
+		t.Fatalf("Expected ErrChecksumMismatch, got %v", err)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, WithCompression(8))
+	reader := NewReader(&buf, WithDecompression())
+
+	data := bytes.Repeat([]byte("a"), 256)
+	if _, err := writer.Write(1, data); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	gotMsgType, gotData, release, err := reader.ReadMsg()
+	if err != nil {
+		t.Fatalf("Error while reading: %v", err)
+	}
+	defer release()
+
+	if gotMsgType != 1 {
+		t.Fatalf("Expected type 1, got %v", gotMsgType)
+	}
+
+	if !bytes.Equal(gotData, data) {
+		t.Fatalf("message sent and recieved not identical")
+	}
+}
+
+func TestDecompressionBoundsGzipBomb(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf, WithCompression(1))
+
+	// Highly compressible data that decompresses to far more than the Reader's MaxMessageSize, even though its
+	// compressed form on the wire is tiny.
+	data := bytes.Repeat([]byte("a"), 1<<20)
+	if _, err := writer.Write(1, data); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	reader := NewReader(&buf, WithDecompression(), WithMaxMessageSize(4096))
+
+	if _, _, _, err := reader.ReadMsg(); err != ErrDecompressedMessageTooLarge {
+		t.Fatalf("Expected ErrDecompressedMessageTooLarge, got %v", err)
+	}
+}
+
+func TestDecompressionIgnoresBodyMagicBytes(t *testing.T) {
+	var buf bytes.Buffer
+	// The threshold is never met, so nothing writer writes is ever actually compressed.
+	writer := NewWriter(&buf, WithCompression(1<<20))
+	reader := NewReader(&buf, WithDecompression())
+
+	// A legitimate uncompressed payload that happens to start with the gzip magic bytes.
+	data := append([]byte{0x1f, 0x8b}, []byte("not actually gzip")...)
+	if _, err := writer.Write(1, data); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	_, gotData, release, err := reader.ReadMsg()
+	if err != nil {
+		t.Fatalf("Error while reading: %v", err)
+	}
+	defer release()
+
+	if !bytes.Equal(gotData, data) {
+		t.Fatalf("message sent and recieved not identical")
+	}
+}
+
+// negativeLengthCodec simulates a malformed or malicious peer declaring a negative message length.
+type negativeLengthCodec struct{}
+
+func (negativeLengthCodec) EncodeHeader(w io.Writer, msgType, msgLen int) error {
+	return VarintCodec{}.EncodeHeader(w, msgType, msgLen)
+}
+
+func (negativeLengthCodec) DecodeHeader(r io.Reader) (int, int, error) {
+	return 0, -1, nil
+}
+
+func TestReadRejectsNegativeLength(t *testing.T) {
+	reader := NewReader(bytes.NewReader(nil), WithReaderCodec(negativeLengthCodec{}))
+
+	if _, _, err := reader.Read(make([]byte, 10)); err != ErrInvalidMessageLength {
+		t.Fatalf("Expected ErrInvalidMessageLength, got %v", err)
+	}
+}
+
+func TestReadWithDecompressionConfigured(t *testing.T) {
+	var buf bytes.Buffer
+	reader := NewReader(&buf, WithDecompression())
+
+	if _, _, err := reader.Read(make([]byte, 10)); err != ErrReadRequiresReadMsg {
+		t.Fatalf("Expected ErrReadRequiresReadMsg, got %v", err)
+	}
+}
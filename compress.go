@@ -0,0 +1,70 @@
+package buffstream
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+// ErrDecompressedMessageTooLarge is returned when a compressed message's decompressed size exceeds the Reader's
+// MaxMessageSize, protecting against a small on-wire message that decompresses into a huge one (a "gzip bomb")
+var ErrDecompressedMessageTooLarge = errors.New("decompressed message size exceeds the Reader's configured maximum")
+
+// notCompressed or isCompressed, written as a single flag byte immediately after the header whenever a Writer is configured with
+// WithCompression, tells the Reader whether this particular message's body is gzip-compressed. Whether a message
+// is compressed is decided purely by this explicit flag, never by inspecting the body: a legitimate uncompressed
+// body can coincidentally start with the gzip magic bytes, so sniffing the body is not a safe way to detect it.
+const (
+	notCompressed byte = 0
+	isCompressed  byte = 1
+)
+
+// gzipCompress gzips data into a buffer drawn from pool.
+func gzipCompress(pool Pool, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+
+	if _, err := gz.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	out := pool.Get(buf.Len())
+	copy(out, buf.Bytes())
+
+	return out, nil
+}
+
+// gunzip decompresses body, which must be a complete gzip stream, into a buffer drawn from pool. If maxSize is
+// positive, decompression is capped at maxSize bytes and ErrDecompressedMessageTooLarge is returned if body
+// decompresses to more than that, so a small compressed message can't be used to exhaust memory.
+func gunzip(pool Pool, body []byte, maxSize int64) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var src io.Reader = gz
+	if maxSize > 0 {
+		src = io.LimitReader(gz, maxSize+1)
+	}
+
+	decoded, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxSize > 0 && int64(len(decoded)) > maxSize {
+		return nil, ErrDecompressedMessageTooLarge
+	}
+
+	out := pool.Get(len(decoded))
+	copy(out, decoded)
+
+	return out, nil
+}
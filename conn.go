@@ -0,0 +1,80 @@
+package buffstream
+
+import "net"
+
+// Conn wraps a net.Conn, combining a Reader and a Writer into a single message-oriented connection so callers don't
+// have to juggle both separately.
+type Conn struct {
+	net.Conn
+
+	reader *Reader
+	writer *Writer
+}
+
+// ConnOption configures the Reader and/or Writer underlying a Conn created by NewConn or a Listener created by
+// NewListener.
+type ConnOption func(*Conn)
+
+// WithConnReaderOption applies a ReaderOption to the Reader underlying a Conn.
+func WithConnReaderOption(opt ReaderOption) ConnOption {
+	return func(c *Conn) {
+		opt(c.reader)
+	}
+}
+
+// WithConnWriterOption applies a WriterOption to the Writer underlying a Conn.
+func WithConnWriterOption(opt WriterOption) ConnOption {
+	return func(c *Conn) {
+		opt(c.writer)
+	}
+}
+
+// NewConn wraps conn, framing messages with buffstream's Reader and Writer.
+func NewConn(conn net.Conn, opts ...ConnOption) *Conn {
+	c := &Conn{
+		Conn:   conn,
+		reader: NewReader(conn),
+		writer: NewWriter(conn),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// ReadMessage reads the next pending message and returns its type and data. See Reader.ReadMsg for the allocation
+// and release contract.
+func (c *Conn) ReadMessage() (msgType int, data []byte, release func(), err error) {
+	return c.reader.ReadMsg()
+}
+
+// WriteMessage writes data as a single message of the given type. See Writer.Write for the delivery contract.
+func (c *Conn) WriteMessage(msgType int, data []byte) error {
+	_, err := c.writer.Write(msgType, data)
+	return err
+}
+
+// Listener wraps a net.Listener, returning framed *Conn connections from Accept instead of plain net.Conn.
+type Listener struct {
+	net.Listener
+
+	opts []ConnOption
+}
+
+// NewListener wraps l so that Accept returns framed *Conn connections. Any ConnOption values passed in are applied
+// to every Conn returned by Accept.
+func NewListener(l net.Listener, opts ...ConnOption) *Listener {
+	return &Listener{Listener: l, opts: opts}
+}
+
+// Accept waits for and returns the next incoming connection, framed as a *Conn.
+func (l *Listener) Accept() (*Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(conn, l.opts...), nil
+}
@@ -0,0 +1,110 @@
+package buffstream
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConn(t *testing.T) {
+	addr, err := net.ResolveTCPAddr("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Error while resolving tcp address: %v", err)
+	}
+
+	tcpListener, err := net.ListenTCP("tcp", addr)
+	if err != nil {
+		t.Fatalf("Error while listening to tcp: %v", err)
+	}
+	listener := NewListener(tcpListener)
+
+	dialConn, err := net.DialTCP("tcp", nil, tcpListener.Addr().(*net.TCPAddr))
+	if err != nil {
+		t.Fatalf("Error while dialing tcp: %v", err)
+	}
+	dial := NewConn(dialConn)
+
+	accept, err := listener.Accept()
+	if err != nil {
+		t.Fatalf("Error while accepting tcp: %v", err)
+	}
+
+	msgs := make([]msg, 10)
+	for i := 0; i < len(msgs); i++ {
+		msgs[i].msgType = rand.Intn(50)
+		msgs[i].data = make([]byte, rand.Intn(50))
+		if _, err := rand.Read(msgs[i].data); err != nil {
+			t.Fatalf("Error while reading random bytes: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for i, m := range msgs {
+			if err := dial.WriteMessage(m.msgType, m.data); err != nil {
+				t.Fatalf("Error while writing message %v: %v", i, err)
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		for i, m := range msgs {
+			gotMsgType, data, release, err := accept.ReadMessage()
+			if err != nil {
+				t.Fatalf("Error while reading message %v: %v", i, err)
+			}
+
+			if gotMsgType != m.msgType {
+				t.Fatalf("Error on message %v, expected type %v, got %v", i, m.msgType, gotMsgType)
+			}
+
+			if !bytes.Equal(data, m.data) {
+				t.Fatalf("Error on message %v, message sent and recieved not identical", i)
+			}
+
+			release()
+		}
+	}()
+
+	wg.Wait()
+
+	if err := dial.Close(); err != nil {
+		t.Fatalf("Error while closing dial conn: %v", err)
+	}
+
+	if err := accept.Close(); err != nil {
+		t.Fatalf("Error while closing accepted conn: %v", err)
+	}
+}
+
+func TestConnOptions(t *testing.T) {
+	var buf bytes.Buffer
+	conn := NewConn(&loopbackConn{Buffer: &buf}, WithConnReaderOption(WithMaxMessageSize(4)))
+
+	if err := conn.WriteMessage(0, make([]byte, 10)); err != nil {
+		t.Fatalf("Error while writing: %v", err)
+	}
+
+	if _, _, _, err := conn.ReadMessage(); err != ErrMessageTooLarge {
+		t.Fatalf("Expected ErrMessageTooLarge, got %v", err)
+	}
+}
+
+// loopbackConn adapts a bytes.Buffer to the net.Conn interface so NewConn can be exercised without a real socket
+type loopbackConn struct {
+	*bytes.Buffer
+}
+
+func (c *loopbackConn) Close() error                       { return nil }
+func (c *loopbackConn) LocalAddr() net.Addr                { return nil }
+func (c *loopbackConn) RemoteAddr() net.Addr               { return nil }
+func (c *loopbackConn) SetDeadline(t time.Time) error      { return nil }
+func (c *loopbackConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *loopbackConn) SetWriteDeadline(t time.Time) error { return nil }
@@ -0,0 +1,83 @@
+package buffstream
+
+// ReaderOption configures a Reader created by NewReader.
+type ReaderOption func(*Reader)
+
+// WithMaxMessageSize configures a Reader to return ErrMessageTooLarge instead of reading any message whose declared
+// length exceeds maxMessageSize. A maxMessageSize of 0 disables the check.
+func WithMaxMessageSize(maxMessageSize int64) ReaderOption {
+	return func(r *Reader) {
+		r.maxMessageSize = maxMessageSize
+	}
+}
+
+// WithReaderPool configures a Reader to draw its ReadMsg buffers from pool instead of the default Pool.
+func WithReaderPool(pool Pool) ReaderOption {
+	return func(r *Reader) {
+		r.pool = pool
+	}
+}
+
+// WithReaderCodec configures a Reader to decode headers with codec instead of the default VarintCodec. The Writer
+// on the other end of the stream must be configured with a matching FrameCodec.
+func WithReaderCodec(codec FrameCodec) ReaderOption {
+	return func(r *Reader) {
+		r.codec = codec
+	}
+}
+
+// WithChecksumReader configures a Reader to expect a trailing CRC32 checksum on every message body, verifying and
+// stripping it before returning data to the caller. The Writer on the other end must be configured with
+// WithChecksumWriter. Read's buffer-too-small check accounts for the trailer, so the buffer passed to Read only
+// needs to fit the payload, not the payload plus the checksum.
+func WithChecksumReader() ReaderOption {
+	return func(r *Reader) {
+		r.checksum = true
+	}
+}
+
+// WithDecompression configures a Reader to transparently gunzip message bodies flagged as compressed by a Writer
+// configured with WithCompression. Whether a given message was compressed is read from that flag, never guessed
+// from the body's contents, so a legitimate uncompressed body is never misdetected as gzip data. Because the
+// decompressed size can differ from the size declared on the wire, decompression is only supported through
+// ReadMsg; Read returns ErrReadRequiresReadMsg if this option is set. Pair this with WithMaxMessageSize so a small
+// compressed message can't be used to exhaust memory by decompressing into a huge one.
+func WithDecompression() ReaderOption {
+	return func(r *Reader) {
+		r.decompress = true
+	}
+}
+
+// WriterOption configures a Writer created by NewWriter.
+type WriterOption func(*Writer)
+
+// WithWriterPool configures a Writer to draw its scratch buffers from pool instead of the default Pool.
+func WithWriterPool(pool Pool) WriterOption {
+	return func(w *Writer) {
+		w.pool = pool
+	}
+}
+
+// WithWriterCodec configures a Writer to encode headers with codec instead of the default VarintCodec. The Reader
+// on the other end of the stream must be configured with a matching FrameCodec.
+func WithWriterCodec(codec FrameCodec) WriterOption {
+	return func(w *Writer) {
+		w.codec = codec
+	}
+}
+
+// WithChecksumWriter configures a Writer to append a trailing CRC32 checksum to every message body, letting a
+// Reader configured with WithChecksumReader detect corruption.
+func WithChecksumWriter() WriterOption {
+	return func(w *Writer) {
+		w.checksum = true
+	}
+}
+
+// WithCompression configures a Writer to gzip message bodies of at least threshold bytes before writing them,
+// flagging each message so a Reader configured with WithDecompression knows whether to reverse it.
+func WithCompression(threshold int) WriterOption {
+	return func(w *Writer) {
+		w.compressThreshold = threshold
+	}
+}
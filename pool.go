@@ -0,0 +1,34 @@
+package buffstream
+
+import "sync"
+
+// Pool is implemented by types that can hand out byte slices for use as message buffers and reclaim them once the
+// caller is done. Get must return a slice of length n. Put returns a slice previously obtained from Get back to the
+// pool; implementations are free to ignore it.
+type Pool interface {
+	Get(n int) []byte
+	Put([]byte)
+}
+
+// defaultPool is the Pool used by NewReader and NewWriter when none is supplied. It is backed by a sync.Pool of
+// byte slices, so buffers are reused across messages instead of being allocated from scratch every time.
+type defaultPool struct {
+	pool sync.Pool
+}
+
+// NewPool creates a new Pool backed by a sync.Pool.
+func NewPool() Pool {
+	return &defaultPool{}
+}
+
+func (p *defaultPool) Get(n int) []byte {
+	if b, ok := p.pool.Get().([]byte); ok && cap(b) >= n {
+		return b[:n]
+	}
+
+	return make([]byte, n)
+}
+
+func (p *defaultPool) Put(b []byte) {
+	p.pool.Put(b[:0])
+}
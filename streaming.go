@@ -0,0 +1,80 @@
+package buffstream
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrMessageLengthMismatch is returned by the io.WriteCloser from Writer.NextMessage when more bytes are written to
+// it than were declared, or when Close is called before the declared number of bytes has been written
+var ErrMessageLengthMismatch = errors.New("bytes written to message did not match its declared length")
+
+// NextMessage returns the type, declared length, and a bounded io.Reader for the next pending message, letting
+// callers stream a large message (e.g. via io.Copy into a file or hasher) without allocating a buffer sized to fit
+// the whole body. The returned io.Reader must be fully consumed before the next call to NextMessage, Read, or
+// ReadMsg, or the stream will desync. Checksum verification and decompression are not applied to messages read this
+// way, since both require the whole body before it can be handed to the caller.
+func (r *Reader) NextMessage() (msgType int, msgLen int, body io.Reader, err error) {
+	length, typ, err := r.readHeader()
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	return int(typ), int(length), &io.LimitedReader{R: r.reader, N: length}, nil
+}
+
+// messageWriter is the io.WriteCloser returned by Writer.NextMessage. It holds the Writer's lock for its entire
+// lifetime, so that no other message can be interleaved with its body before Close is called.
+type messageWriter struct {
+	w         *Writer
+	remaining int
+	closed    bool
+}
+
+// Write implements io.Writer
+func (mw *messageWriter) Write(p []byte) (int, error) {
+	if mw.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	if len(p) > mw.remaining {
+		return 0, ErrMessageLengthMismatch
+	}
+
+	n, err := mw.w.writer.Write(p)
+	mw.remaining -= n
+	return n, err
+}
+
+// Close implements io.Closer, releasing the Writer's lock. It returns ErrMessageLengthMismatch if fewer bytes than
+// declared were written to it.
+func (mw *messageWriter) Close() error {
+	if mw.closed {
+		return nil
+	}
+	mw.closed = true
+	defer mw.w.mu.Unlock()
+
+	if mw.remaining != 0 {
+		return ErrMessageLengthMismatch
+	}
+
+	return nil
+}
+
+// NextMessage writes the header for a message of msgLen bytes of the given msgType upfront and returns an
+// io.WriteCloser that streams the body, letting callers write a large message (e.g. via io.Copy from a file)
+// without buffering the whole body up front. The caller must write exactly msgLen bytes and then call Close, which
+// errors if the declared length wasn't met. No other Write or NextMessage call on this Writer will proceed until
+// Close is called. Checksumming and compression are not applied to messages written this way, since both require
+// knowing the whole body before the header can be written.
+func (w *Writer) NextMessage(msgType, msgLen int) (io.WriteCloser, error) {
+	w.mu.Lock()
+
+	if err := w.codec.EncodeHeader(w.writer, msgType, msgLen); err != nil {
+		w.mu.Unlock()
+		return nil, err
+	}
+
+	return &messageWriter{w: w, remaining: msgLen}, nil
+}
@@ -0,0 +1,72 @@
+package buffstream
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestNextMessage(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+
+	data := make([]byte, 1024)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("Error while reading random bytes: %v", err)
+	}
+
+	wc, err := writer.NextMessage(7, len(data))
+	if err != nil {
+		t.Fatalf("Error while starting message: %v", err)
+	}
+
+	if _, err := io.Copy(wc, bytes.NewReader(data)); err != nil {
+		t.Fatalf("Error while streaming body: %v", err)
+	}
+
+	if err := wc.Close(); err != nil {
+		t.Fatalf("Error while closing message: %v", err)
+	}
+
+	reader := NewReader(&buf)
+	msgType, msgLen, body, err := reader.NextMessage()
+	if err != nil {
+		t.Fatalf("Error while reading message: %v", err)
+	}
+
+	if msgType != 7 {
+		t.Fatalf("Expected type 7, got %v", msgType)
+	}
+
+	if msgLen != len(data) {
+		t.Fatalf("Expected length %v, got %v", len(data), msgLen)
+	}
+
+	var got bytes.Buffer
+	if _, err := io.Copy(&got, body); err != nil {
+		t.Fatalf("Error while reading body: %v", err)
+	}
+
+	if !bytes.Equal(got.Bytes(), data) {
+		t.Fatalf("message sent and recieved not identical")
+	}
+}
+
+func TestNextMessageLengthMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	writer := NewWriter(&buf)
+
+	wc, err := writer.NextMessage(1, 10)
+	if err != nil {
+		t.Fatalf("Error while starting message: %v", err)
+	}
+
+	if _, err := wc.Write(make([]byte, 5)); err != nil {
+		t.Fatalf("Error while writing body: %v", err)
+	}
+
+	if err := wc.Close(); err != ErrMessageLengthMismatch {
+		t.Fatalf("Expected ErrMessageLengthMismatch, got %v", err)
+	}
+}